@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"load-balancer/internal/balancer"
 	"load-balancer/internal/ratelimiter"
@@ -22,26 +25,76 @@ func main() {
 	}
 	defer lb.Close()
 
-	// Add backends
-	backends := []string{
-		"http://ms-backend:5001",
-		"http://ms-backend1:5000",
-		"http://ms-backend2:5002",
-		"http://ms-backend3:5003",
+	// Add backends, each with its HTTP and WebSocket endpoint
+	backends := []struct {
+		http string
+		ws   string
+	}{
+		{"http://ms-backend:5001", "ws://ms-backend:5001"},
+		{"http://ms-backend1:5000", "ws://ms-backend1:5000"},
+		{"http://ms-backend2:5002", "ws://ms-backend2:5002"},
+		{"http://ms-backend3:5003", "ws://ms-backend3:5003"},
 	}
 
 	for _, backend := range backends {
-		if err := lb.AddBackend(backend); err != nil {
-			logger.Printf("Failed to add backend %s: %v", backend, err)
+		if err := lb.AddBackend(backend.http, backend.ws); err != nil {
+			logger.Printf("Failed to add backend %s: %v", backend.http, err)
 		} else {
-			fmt.Printf("Added backend: %s\n", backend)
+			fmt.Printf("Added backend: %s\n", backend.http)
 		}
 	}
 
+	lb.StartConsensusPoller(context.Background())
+
+	frontendConfig, err := ratelimiter.LoadFrontendConfig("configs/frontend_ratelimit.toml")
+	if err != nil {
+		log.Fatalf("Failed to load frontend rate limit config: %v", err)
+	}
+	frontendRateLimiter, err := ratelimiter.NewFrontendRateLimiter(lb.RedisClient(), frontendConfig)
+	if err != nil {
+		log.Fatalf("Failed to create frontend rate limiter: %v", err)
+	}
+
 	methodRateLimiter := ratelimiter.NewMethodRateLimiter(10, 60)
+	senderRateLimiter := ratelimiter.NewSenderRateLimiter(lb.RedisClient(), 1, 5)
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		allowed, err := methodRateLimiter.Allow(r)
+		allowed, err := frontendRateLimiter.Allow(r)
+		if err != nil {
+			logger.Printf("Error checking frontend rate limit: %v", err)
+			http.Error(w, "Error checking rate limit", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			logger.Printf("Frontend rate limit exceeded for %s from %s", r.URL.Path, r.RemoteAddr)
+			writeRateLimitError(w)
+			return
+		}
+
+		if balancer.IsWebSocketUpgrade(r) {
+			lb.ServeWebSocket(w, r)
+			return
+		}
+
+		allowed, err = senderRateLimiter.Allow(r)
+		if err != nil {
+			var invalidTx *ratelimiter.InvalidTransactionError
+			if errors.As(err, &invalidTx) {
+				logger.Printf("Rejecting malformed eth_sendRawTransaction from %s: %v", r.RemoteAddr, err)
+				writeInvalidParamsError(w, err)
+				return
+			}
+			logger.Printf("Error checking sender rate limit: %v", err)
+			http.Error(w, "Error checking rate limit", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			logger.Printf("Sender rate limit exceeded for %s from %s", r.URL.Path, r.RemoteAddr)
+			writeSenderRateLimitError(w)
+			return
+		}
+
+		allowed, err = methodRateLimiter.Allow(r)
 		if err != nil {
 			logger.Printf("Error checking rate limit: %v", err)
 			http.Error(w, "Error checking rate limit", http.StatusInternalServerError)
@@ -49,13 +102,79 @@ func main() {
 		}
 		if !allowed {
 			logger.Printf("Rate limit exceeded for %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
-			http.Error(w, "Rate limit exceeded or request body too large", http.StatusTooManyRequests)
+			writeRateLimitError(w)
 			return
 		}
 
 		lb.ServeHTTP(w, r)
 	})
 
+	http.HandleFunc("/admin/cache/clear", func(w http.ResponseWriter, r *http.Request) {
+		if err := lb.ClearCache(r.Context()); err != nil {
+			logger.Printf("Error clearing cache: %v", err)
+			http.Error(w, "Failed to clear cache", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	http.HandleFunc("/admin/consensus", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(lb.ConsensusStatus())
+	})
+
+	http.HandleFunc("/admin/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(lb.Health())
+	})
+
 	fmt.Println("Load balancer is running on :8080")
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }
+
+// writeRateLimitError writes a 429 response with a JSON-RPC error body,
+// rather than a plain text error, so RPC clients can parse it normally.
+func writeRateLimitError(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      nil,
+		"error": map[string]interface{}{
+			"code":    -32000,
+			"message": "rate limit exceeded",
+		},
+	})
+}
+
+// writeInvalidParamsError writes a 400 response with a JSON-RPC invalid
+// params error, for an eth_sendRawTransaction call whose sender couldn't be
+// determined (bad hex, bad RLP, failed signature recovery) — a client
+// mistake, not a server fault.
+func writeInvalidParamsError(w http.ResponseWriter, cause error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      nil,
+		"error": map[string]interface{}{
+			"code":    -32602,
+			"message": fmt.Sprintf("invalid params: %v", cause),
+		},
+	})
+}
+
+// writeSenderRateLimitError writes a 429 response for an
+// eth_sendRawTransaction call whose sender has exceeded its rate limit.
+func writeSenderRateLimitError(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      nil,
+		"error": map[string]interface{}{
+			"code":    -32005,
+			"message": "sender is over rate limit",
+		},
+	})
+}
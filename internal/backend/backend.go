@@ -1,13 +1,97 @@
 package backend
 
 import (
+	"context"
 	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
 )
 
 type Backend struct {
 	URL *url.URL
+	// WSURL is the WebSocket endpoint for this backend, used for
+	// eth_subscribe-style streaming. May be nil if the backend doesn't
+	// support WebSocket.
+	WSURL *url.URL
+
+	rdb *redis.Client
+
+	tipMutex    sync.RWMutex
+	blockNumber uint64
+	blockHash   string
+	lastUpdate  time.Time
+}
+
+// NewBackend creates a Backend that shares rdb with the LoadBalancer, so its
+// online/rate-limited state is visible to every load balancer replica.
+func NewBackend(url *url.URL, wsURL *url.URL, rdb *redis.Client) *Backend {
+	return &Backend{URL: url, WSURL: wsURL, rdb: rdb}
+}
+
+// SetTip records the latest block this backend has reported.
+func (b *Backend) SetTip(blockNumber uint64, blockHash string) {
+	b.tipMutex.Lock()
+	defer b.tipMutex.Unlock()
+
+	b.blockNumber = blockNumber
+	b.blockHash = blockHash
+	b.lastUpdate = time.Now()
+}
+
+// Tip returns the most recently observed (blockNumber, blockHash, lastUpdate)
+// for this backend.
+func (b *Backend) Tip() (blockNumber uint64, blockHash string, lastUpdate time.Time) {
+	b.tipMutex.RLock()
+	defer b.tipMutex.RUnlock()
+
+	return b.blockNumber, b.blockHash, b.lastUpdate
 }
 
-func NewBackend(url *url.URL) *Backend {
-	return &Backend{URL: url}
-}
\ No newline at end of file
+func (b *Backend) offlineKey() string {
+	return "backend:offline:" + b.URL.String()
+}
+
+func (b *Backend) rateLimitedKey() string {
+	return "backend:ratelimited:" + b.URL.String()
+}
+
+// Online reports whether this backend is not currently in an offline
+// cooldown window. The cooldown is stored in Redis so it is shared across
+// load balancer replicas.
+func (b *Backend) Online(ctx context.Context) bool {
+	until, err := b.rdb.Get(ctx, b.offlineKey()).Int64()
+	if err == redis.Nil {
+		return true
+	}
+	if err != nil {
+		return true // fail open: don't take a backend out of rotation on a Redis hiccup
+	}
+	return time.Now().Unix() >= until
+}
+
+// IsRateLimited reports whether this backend is currently in a rate-limit
+// cooldown window.
+func (b *Backend) IsRateLimited(ctx context.Context) bool {
+	until, err := b.rdb.Get(ctx, b.rateLimitedKey()).Int64()
+	if err == redis.Nil {
+		return false
+	}
+	if err != nil {
+		return false
+	}
+	return time.Now().Unix() < until
+}
+
+// SetOffline takes this backend out of rotation for cooldown.
+func (b *Backend) SetOffline(ctx context.Context, cooldown time.Duration) error {
+	until := time.Now().Add(cooldown).Unix()
+	return b.rdb.Set(ctx, b.offlineKey(), until, cooldown).Err()
+}
+
+// SetRateLimited marks this backend as rate limited for cooldown.
+func (b *Backend) SetRateLimited(ctx context.Context, cooldown time.Duration) error {
+	until := time.Now().Add(cooldown).Unix()
+	return b.rdb.Set(ctx, b.rateLimitedKey(), until, cooldown).Err()
+}
@@ -1,17 +1,20 @@
 package balancer
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"log"
 	"net/http"
-	"net/http/httputil"
 	"net/url"
 	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"load-balancer/internal/backend"
+	"load-balancer/internal/cache"
 )
 
 type LoadBalancer struct {
@@ -19,8 +22,45 @@ type LoadBalancer struct {
 	mutex    sync.RWMutex
 	rdb      *redis.Client
 	logger   *log.Logger
+	cache    cache.RPCCache
+
+	consensus consensusState
+
+	// MaxUpstreamBatchSize bounds how many JSON-RPC calls of the same
+	// method are sent to a single backend in one batch.
+	MaxUpstreamBatchSize int
+
+	// ConsensusInterval is how often the consensus poller re-checks every
+	// backend's tip. Defaults to defaultConsensusInterval.
+	ConsensusInterval time.Duration
+
+	// MaxBlockLag is how many blocks behind the consensus tip a backend
+	// may be and still be eligible for selection. Defaults to
+	// defaultMaxBlockLag.
+	MaxBlockLag uint64
+
+	// MaxRetries is how many additional backends are tried after the first
+	// one fails with a 5xx, connection error, or 429. Defaults to
+	// defaultMaxRetries.
+	MaxRetries int
+
+	// WSHandshakeTimeout, WSReadTimeout, and WSWriteTimeout bound the
+	// WebSocket upgrade handshake and subsequent frame I/O. Default to
+	// defaultWSHandshakeTimeout/defaultWSReadTimeout/defaultWSWriteTimeout.
+	WSHandshakeTimeout time.Duration
+	WSReadTimeout      time.Duration
+	WSWriteTimeout     time.Duration
+
+	// WSMethodWhitelist restricts which JSON-RPC methods a client may send
+	// over an established WebSocket connection. Defaults to
+	// defaultWSMethodWhitelist.
+	WSMethodWhitelist map[string]bool
 }
 
+// defaultHotCacheItems bounds the in-process LRU tier sitting in front of
+// the shared Redis cache.
+const defaultHotCacheItems = 1024
+
 func NewLoadBalancer(redisAddr string, logger *log.Logger) (*LoadBalancer, error) {
 	rdb := redis.NewClient(&redis.Options{
 		Addr: redisAddr,
@@ -33,21 +73,46 @@ func NewLoadBalancer(redisAddr string, logger *log.Logger) (*LoadBalancer, error
 	}
 
 	return &LoadBalancer{
-		rdb:    rdb,
-		logger: logger,
+		rdb:                  rdb,
+		logger:               logger,
+		cache:                cache.NewTieredCache(cache.NewRedisCache(rdb, "rpccache:"), defaultHotCacheItems),
+		MaxUpstreamBatchSize: 10,
 	}, nil
 }
 
-func (lb *LoadBalancer) AddBackend(urlStr string) error {
-	url, err := url.Parse(urlStr)
+// RedisClient exposes the LoadBalancer's Redis connection so callers (e.g.
+// other rate limiters) can share it instead of opening their own.
+func (lb *LoadBalancer) RedisClient() *redis.Client {
+	return lb.rdb
+}
+
+// ClearCache drops all cached JSON-RPC responses. Intended to be called when
+// a reorg is observed and cached results can no longer be trusted.
+func (lb *LoadBalancer) ClearCache(ctx context.Context) error {
+	return lb.cache.Clear(ctx)
+}
+
+// AddBackend registers a backend reachable over HTTP at urlStr and,
+// optionally, over WebSocket at wsURLStr (pass "" if the backend has no
+// WebSocket endpoint).
+func (lb *LoadBalancer) AddBackend(urlStr string, wsURLStr string) error {
+	backendURL, err := url.Parse(urlStr)
 	if err != nil {
 		return err
 	}
 
+	var wsURL *url.URL
+	if wsURLStr != "" {
+		wsURL, err = url.Parse(wsURLStr)
+		if err != nil {
+			return err
+		}
+	}
+
 	lb.mutex.Lock()
 	defer lb.mutex.Unlock()
 
-	newBackend := backend.NewBackend(url)
+	newBackend := backend.NewBackend(backendURL, wsURL, lb.rdb)
 	lb.backends = append(lb.backends, newBackend)
 
 	ctx := context.Background()
@@ -68,17 +133,49 @@ func (lb *LoadBalancer) NextBackend() (*backend.Backend, error) {
 	}
 
 	ctx := context.Background()
-	
+
+	// Offline or rate-limited backends are never eligible, no matter what.
+	healthy := make([]*backend.Backend, 0, len(lb.backends))
+	for _, b := range lb.backends {
+		if b.Online(ctx) && !b.IsRateLimited(ctx) {
+			healthy = append(healthy, b)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil, errors.New("no healthy backends available")
+	}
+
+	// Only consider backends that are caught up with the consensus tip; if
+	// none are (or no consensus has been reached), fall back to all healthy
+	// backends.
+	candidates := make([]*backend.Backend, 0, len(healthy))
+	for _, b := range healthy {
+		if lb.isWithinConsensus(b) {
+			candidates = append(candidates, b)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = healthy
+	}
+
 	// Get all backend response times
 	responseTimes, err := lb.rdb.HGetAll(ctx, "backends").Result()
 	if err != nil {
 		return nil, err
 	}
 
+	candidateSet := make(map[string]bool, len(candidates))
+	for _, b := range candidates {
+		candidateSet[b.URL.String()] = true
+	}
+
 	var minResponseTime float64 = float64(^uint64(0) >> 1) // Max float64 value
 	var selectedBackendURL string
 
 	for urlStr, responseTimeStr := range responseTimes {
+		if !candidateSet[urlStr] {
+			continue
+		}
 		responseTime, _ := time.ParseDuration(responseTimeStr)
 		if responseTime.Seconds() < minResponseTime {
 			minResponseTime = responseTime.Seconds()
@@ -105,28 +202,44 @@ func (lb *LoadBalancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Log incoming request
 	lb.logRequest(r, start)
 
-	backend, err := lb.NextBackend()
-	if err != nil {
-		http.Error(w, "No backend available", http.StatusServiceUnavailable)
-		lb.logError(r, err, start)
-		return
-	}
-
-	proxy := httputil.NewSingleHostReverseProxy(backend.URL)
-	
-	// Wrap the ResponseWriter to capture the status code
-	wrappedWriter := &responseWriterWrapper{ResponseWriter: w, statusCode: http.StatusOK}
-	
-	proxy.ServeHTTP(wrappedWriter, r)
+	var cacheKey string
+	cacheable := false
+	var body []byte
+
+	if r.Method == http.MethodPost {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			lb.logError(r, err, start)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
 
-	duration := time.Since(start)
+		if isBatchRequest(body) {
+			lb.serveBatch(w, r, body)
+			return
+		}
 
-	// Update response time in Redis
-	ctx := context.Background()
-	lb.rdb.HSet(ctx, "backends", backend.URL.String(), duration.String())
+		var req RPCReq
+		if err := json.Unmarshal(body, &req); err == nil && isCacheable(req) {
+			cacheable = true
+			cacheKey = rpcCacheKey(req)
+
+			if cached, err := lb.cache.Get(r.Context(), cacheKey); err == nil {
+				w.Header().Set("X-LB-Cache", "HIT")
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(cached))
+				lb.logger.Printf(
+					"[%s] Completed request: %s %s from %s | Cache: HIT | Duration: %v",
+					time.Now().Format(time.RFC3339), req.Method, r.URL.Path, r.RemoteAddr, time.Since(start),
+				)
+				return
+			}
+		}
+	}
 
-	// Log completed request
-	lb.logCompletedRequest(r, wrappedWriter.statusCode, duration, backend.URL)
+	lb.serveWithRetry(w, r, start, body, cacheable, cacheKey)
 }
 
 func (lb *LoadBalancer) Close() error {
@@ -170,14 +283,3 @@ func (lb *LoadBalancer) logError(r *http.Request, err error, start time.Time) {
 		time.Since(start),
 	)
 }
-
-// responseWriterWrapper is a custom ResponseWriter that captures the status code
-type responseWriterWrapper struct {
-	http.ResponseWriter
-	statusCode int
-}
-
-func (rww *responseWriterWrapper) WriteHeader(statusCode int) {
-	rww.statusCode = statusCode
-	rww.ResponseWriter.WriteHeader(statusCode)
-}
\ No newline at end of file
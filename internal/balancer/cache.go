@@ -0,0 +1,63 @@
+package balancer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// cacheTTL is how long a cached JSON-RPC response is considered fresh.
+const cacheTTL = 10 * time.Minute
+
+// cacheableMethods lists JSON-RPC methods whose results are immutable, or
+// change slowly enough, to be safely cached.
+var cacheableMethods = map[string]bool{
+	"eth_chainId":               true,
+	"eth_getBlockByNumber":      true,
+	"eth_getTransactionReceipt": true,
+	"eth_getBlockReceipts":      true,
+}
+
+// blockTagMethods lists methods whose first param may be either a concrete
+// block number or a moving tag ("latest", "pending", "safe"); a call made
+// with a moving tag must never be cached, since the key doesn't change as
+// the tag's target block does.
+var blockTagMethods = map[string]bool{
+	"eth_getBlockByNumber": true,
+	"eth_getBlockReceipts": true,
+}
+
+// isCacheable reports whether req is safe to cache. Methods in
+// blockTagMethods are only cacheable when called with a concrete block
+// number, never a moving tag.
+func isCacheable(req RPCReq) bool {
+	if !cacheableMethods[req.Method] {
+		return false
+	}
+
+	if blockTagMethods[req.Method] {
+		var params []json.RawMessage
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 {
+			return false
+		}
+		var tag string
+		if err := json.Unmarshal(params[0], &tag); err != nil {
+			// Not a string tag, so it must already be a numeric/hex block
+			// number - safe to cache.
+			return true
+		}
+		switch tag {
+		case "latest", "pending", "safe":
+			return false
+		}
+	}
+
+	return true
+}
+
+// rpcCacheKey derives a cache key from the method and its params.
+func rpcCacheKey(req RPCReq) string {
+	sum := sha256.Sum256(req.Params)
+	return req.Method + ":" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,35 @@
+package balancer
+
+import "testing"
+
+func TestIsCacheable(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		params string
+		want   bool
+	}{
+		{"uncacheable method", "eth_getBalance", ``, false},
+		{"chain id", "eth_chainId", ``, true},
+		{"block by number, concrete", "eth_getBlockByNumber", `["0x10", false]`, true},
+		{"block by number, latest", "eth_getBlockByNumber", `["latest", false]`, false},
+		{"block by number, pending", "eth_getBlockByNumber", `["pending", false]`, false},
+		{"block by number, safe", "eth_getBlockByNumber", `["safe", false]`, false},
+		{"block by number, no params", "eth_getBlockByNumber", `[]`, false},
+		{"transaction receipt", "eth_getTransactionReceipt", `["0xabc"]`, true},
+		{"block receipts, concrete", "eth_getBlockReceipts", `["0x10"]`, true},
+		{"block receipts, latest", "eth_getBlockReceipts", `["latest"]`, false},
+		{"block receipts, pending", "eth_getBlockReceipts", `["pending"]`, false},
+		{"block receipts, safe", "eth_getBlockReceipts", `["safe"]`, false},
+		{"block receipts, no params", "eth_getBlockReceipts", `[]`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := RPCReq{Method: tt.method, Params: []byte(tt.params)}
+			if got := isCacheable(req); got != tt.want {
+				t.Errorf("isCacheable(%+v) = %v, want %v", req, got, tt.want)
+			}
+		})
+	}
+}
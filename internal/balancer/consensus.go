@@ -0,0 +1,286 @@
+package balancer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"load-balancer/internal/backend"
+)
+
+// defaultConsensusInterval is how often the poller re-checks every backend's
+// tip when LoadBalancer.ConsensusInterval is unset.
+const defaultConsensusInterval = 2 * time.Second
+
+// defaultMaxBlockLag is how many blocks behind the consensus tip a backend
+// may be and still be eligible for selection.
+const defaultMaxBlockLag = 2
+
+// consensusQuorum is the minimum fraction of backends that must agree on a
+// (blockNumber, blockHash) pair for it to be treated as the tip.
+const consensusQuorum = 0.5
+
+type blockTip struct {
+	number uint64
+	hash   string
+}
+
+// consensusState is the latest computed tip, or the zero value if no quorum
+// has ever been reached.
+type consensusState struct {
+	mutex sync.RWMutex
+	tip   blockTip
+	ok    bool
+}
+
+func (cs *consensusState) set(tip blockTip) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	cs.tip = tip
+	cs.ok = true
+}
+
+func (cs *consensusState) clear() {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	cs.ok = false
+}
+
+func (cs *consensusState) get() (blockTip, bool) {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+	return cs.tip, cs.ok
+}
+
+// StartConsensusPoller launches a goroutine that periodically polls every
+// backend's latest block and recomputes the consensus tip. It runs until ctx
+// is cancelled.
+func (lb *LoadBalancer) StartConsensusPoller(ctx context.Context) {
+	interval := lb.ConsensusInterval
+	if interval <= 0 {
+		interval = defaultConsensusInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				lb.pollConsensus(ctx)
+			}
+		}
+	}()
+}
+
+func (lb *LoadBalancer) pollConsensus(ctx context.Context) {
+	lb.mutex.RLock()
+	backends := make([]*backend.Backend, len(lb.backends))
+	copy(backends, lb.backends)
+	lb.mutex.RUnlock()
+
+	if len(backends) == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, b := range backends {
+		b := b
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			number, hash, err := lb.fetchLatestBlock(ctx, b)
+			if err != nil {
+				lb.logger.Printf("Consensus poll failed for %s: %v", b.URL, err)
+				return
+			}
+			b.SetTip(number, hash)
+		}()
+	}
+	wg.Wait()
+
+	counts := make(map[blockTip]int)
+	for _, b := range backends {
+		number, hash, _ := b.Tip()
+		if hash == "" {
+			continue
+		}
+		counts[blockTip{number: number, hash: hash}]++
+	}
+
+	quorum := int(float64(len(backends))*consensusQuorum + 0.9999)
+	if quorum < 1 {
+		quorum = 1
+	}
+
+	var best blockTip
+	found := false
+	for tip, count := range counts {
+		if count >= quorum && (!found || tip.number > best.number) {
+			best = tip
+			found = true
+		}
+	}
+
+	if !found {
+		lb.logger.Printf("Consensus broken: no quorum of %d/%d backends agree on a tip", quorum, len(backends))
+		lb.consensus.clear()
+		if err := lb.ClearCache(ctx); err != nil {
+			lb.logger.Printf("Error clearing cache after consensus loss: %v", err)
+		}
+		return
+	}
+
+	lb.consensus.set(best)
+}
+
+// fetchLatestBlock asks a single backend for its latest block via
+// eth_getBlockByNumber("latest", false).
+func (lb *LoadBalancer) fetchLatestBlock(ctx context.Context, b *backend.Backend) (uint64, string, error) {
+	req := RPCReq{
+		JSONRPC: "2.0",
+		Method:  "eth_getBlockByNumber",
+		Params:  json.RawMessage(`["latest", false]`),
+		ID:      json.RawMessage(`1`),
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return 0, "", err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, b.URL.String(), bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	var res RPCRes
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return 0, "", fmt.Errorf("decoding eth_getBlockByNumber response: %w", err)
+	}
+	if res.Error != nil {
+		return 0, "", fmt.Errorf("backend returned error: %s", res.Error.Message)
+	}
+
+	var block struct {
+		Number string `json:"number"`
+		Hash   string `json:"hash"`
+	}
+	if err := json.Unmarshal(res.Result, &block); err != nil {
+		return 0, "", fmt.Errorf("decoding block: %w", err)
+	}
+
+	number, err := parseHexUint64(block.Number)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return number, block.Hash, nil
+}
+
+func parseHexUint64(hexStr string) (uint64, error) {
+	trimmed := hexStr
+	if len(trimmed) > 2 && trimmed[:2] == "0x" {
+		trimmed = trimmed[2:]
+	}
+	return strconv.ParseUint(trimmed, 16, 64)
+}
+
+// ConsensusTip returns the current consensus tip, if one has been reached.
+func (lb *LoadBalancer) ConsensusTip() (number uint64, hash string, ok bool) {
+	tip, ok := lb.consensus.get()
+	return tip.number, tip.hash, ok
+}
+
+// BackendConsensusStatus describes a single backend's view of the chain
+// relative to the consensus tip.
+type BackendConsensusStatus struct {
+	URL         string `json:"url"`
+	BlockNumber uint64 `json:"blockNumber"`
+	BlockHash   string `json:"blockHash"`
+	Lag         uint64 `json:"lag"`
+	Eligible    bool   `json:"eligible"`
+}
+
+// ConsensusStatusReport is the payload served by /admin/consensus.
+type ConsensusStatusReport struct {
+	TipNumber uint64                   `json:"tipNumber"`
+	TipHash   string                   `json:"tipHash"`
+	HasQuorum bool                     `json:"hasQuorum"`
+	Backends  []BackendConsensusStatus `json:"backends"`
+}
+
+// ConsensusStatus reports the current consensus tip and each backend's lag
+// relative to it, for observability.
+func (lb *LoadBalancer) ConsensusStatus() ConsensusStatusReport {
+	lb.mutex.RLock()
+	backends := make([]*backend.Backend, len(lb.backends))
+	copy(backends, lb.backends)
+	lb.mutex.RUnlock()
+
+	tip, ok := lb.consensus.get()
+
+	report := ConsensusStatusReport{
+		TipNumber: tip.number,
+		TipHash:   tip.hash,
+		HasQuorum: ok,
+	}
+
+	for _, b := range backends {
+		number, hash, _ := b.Tip()
+		var lag uint64
+		if ok && number < tip.number {
+			lag = tip.number - number
+		}
+		report.Backends = append(report.Backends, BackendConsensusStatus{
+			URL:         b.URL.String(),
+			BlockNumber: number,
+			BlockHash:   hash,
+			Lag:         lag,
+			Eligible:    lb.isWithinConsensus(b),
+		})
+	}
+
+	return report
+}
+
+// isWithinConsensus reports whether b is eligible for selection: its latest
+// block must be within MaxBlockLag of the consensus tip, with a hash
+// matching the tip when it has fully caught up. If no consensus has been
+// reached yet, every backend is eligible.
+func (lb *LoadBalancer) isWithinConsensus(b *backend.Backend) bool {
+	tip, ok := lb.consensus.get()
+	if !ok {
+		return true
+	}
+
+	maxLag := lb.MaxBlockLag
+	if maxLag == 0 {
+		maxLag = defaultMaxBlockLag
+	}
+
+	number, hash, _ := b.Tip()
+	if number > tip.number || tip.number-number > maxLag {
+		return false
+	}
+	if number == tip.number && hash != tip.hash {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,76 @@
+package balancer
+
+import (
+	"net/url"
+	"testing"
+
+	"load-balancer/internal/backend"
+)
+
+func newTestBackend(t *testing.T, number uint64, hash string) *backend.Backend {
+	t.Helper()
+	u, err := url.Parse("http://backend.invalid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := backend.NewBackend(u, nil, nil)
+	b.SetTip(number, hash)
+	return b
+}
+
+func TestIsWithinConsensus(t *testing.T) {
+	tests := []struct {
+		name       string
+		haveTip    bool
+		tip        blockTip
+		maxLag     uint64
+		number     uint64
+		hash       string
+		wantWithin bool
+	}{
+		{"no consensus yet", false, blockTip{}, 0, 100, "0xabc", true},
+		{"at tip, matching hash", true, blockTip{number: 100, hash: "0xabc"}, 2, 100, "0xabc", true},
+		{"at tip, mismatched hash", true, blockTip{number: 100, hash: "0xabc"}, 2, 100, "0xdef", false},
+		{"within max lag", true, blockTip{number: 100, hash: "0xabc"}, 2, 99, "0x999", true},
+		{"beyond max lag", true, blockTip{number: 100, hash: "0xabc"}, 2, 97, "0x999", false},
+		{"ahead of tip", true, blockTip{number: 100, hash: "0xabc"}, 2, 101, "0x999", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := &LoadBalancer{MaxBlockLag: tt.maxLag}
+			if tt.haveTip {
+				lb.consensus.set(tt.tip)
+			}
+
+			b := newTestBackend(t, tt.number, tt.hash)
+			if got := lb.isWithinConsensus(b); got != tt.wantWithin {
+				t.Errorf("isWithinConsensus() = %v, want %v", got, tt.wantWithin)
+			}
+		})
+	}
+}
+
+func TestParseHexUint64(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint64
+		wantErr bool
+	}{
+		{"0x10", 16, false},
+		{"0x0", 0, false},
+		{"10", 16, false},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseHexUint64(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseHexUint64(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("parseHexUint64(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package balancer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"time"
+
+	"load-balancer/internal/backend"
+)
+
+// defaultMaxRetries is how many additional backends are tried after the
+// first one fails, when LoadBalancer.MaxRetries is unset.
+const defaultMaxRetries = 3
+
+// retryBackoff is the cooldown applied to a failing backend, indexed by the
+// retry attempt number within the current request (clamped to the last
+// entry), not by how many times that backend has failed before.
+var retryBackoff = []time.Duration{1 * time.Second, 5 * time.Second, 30 * time.Second}
+
+func backoffFor(attempt int) time.Duration {
+	if attempt >= len(retryBackoff) {
+		return retryBackoff[len(retryBackoff)-1]
+	}
+	return retryBackoff[attempt]
+}
+
+// serveWithRetry selects a backend, proxies the request, and on a 5xx,
+// connection error, or 429 marks that backend unhealthy and retries against
+// a different one, up to MaxRetries times. body is the already-drained
+// request body (nil for requests without one) so it can be replayed on each
+// attempt.
+func (lb *LoadBalancer) serveWithRetry(w http.ResponseWriter, r *http.Request, start time.Time, body []byte, cacheable bool, cacheKey string) {
+	maxRetries := lb.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	var lastErr error
+
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		b, err := lb.NextBackend()
+		if err != nil {
+			lastErr = err
+			break
+		}
+
+		if body != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		rec := httptest.NewRecorder()
+		proxy := httputil.NewSingleHostReverseProxy(b.URL)
+
+		var proxyErr error
+		proxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, e error) {
+			proxyErr = e
+		}
+
+		if cacheable {
+			proxy.ModifyResponse = lb.cacheModifyResponse(cacheKey)
+		}
+
+		attemptStart := time.Now()
+		proxy.ServeHTTP(rec, r)
+		duration := time.Since(attemptStart)
+
+		ctx := context.Background()
+
+		if proxyErr != nil {
+			lastErr = proxyErr
+			lb.markUnhealthy(ctx, b, attempt, proxyErr)
+			continue
+		}
+
+		if rec.Code >= 500 {
+			lastErr = fmt.Errorf("backend %s returned status %d", b.URL, rec.Code)
+			lb.markUnhealthy(ctx, b, attempt, lastErr)
+			continue
+		}
+
+		if rec.Code == http.StatusTooManyRequests {
+			lastErr = fmt.Errorf("backend %s is rate limited", b.URL)
+			lb.markRateLimited(ctx, b, attempt)
+			continue
+		}
+
+		for k, values := range rec.Header() {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+
+		lb.rdb.HSet(ctx, "backends", b.URL.String(), duration.String())
+		lb.logCompletedRequest(r, rec.Code, duration, b.URL)
+		return
+	}
+
+	lb.logError(r, lastErr, start)
+	writeJSONRPCError(w, http.StatusTooManyRequests, -32619, "rate limited")
+}
+
+// cacheModifyResponse returns a ReverseProxy.ModifyResponse hook that caches
+// successful, non-null results under key.
+func (lb *LoadBalancer) cacheModifyResponse(key string) func(*http.Response) error {
+	return func(resp *http.Response) error {
+		if resp.StatusCode != http.StatusOK {
+			return nil
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+		var parsed RPCRes
+		if err := json.Unmarshal(respBody, &parsed); err == nil && len(parsed.Result) > 0 && string(parsed.Result) != "null" {
+			lb.cache.Put(context.Background(), key, string(respBody), cacheTTL)
+		}
+
+		return nil
+	}
+}
+
+func (lb *LoadBalancer) markUnhealthy(ctx context.Context, b *backend.Backend, attempt int, cause error) {
+	cooldown := backoffFor(attempt)
+	lb.logger.Printf("Marking backend %s offline for %v: %v", b.URL, cooldown, cause)
+	if err := b.SetOffline(ctx, cooldown); err != nil {
+		lb.logger.Printf("Failed to record offline state for %s: %v", b.URL, err)
+	}
+}
+
+func (lb *LoadBalancer) markRateLimited(ctx context.Context, b *backend.Backend, attempt int) {
+	cooldown := backoffFor(attempt)
+	lb.logger.Printf("Marking backend %s rate limited for %v", b.URL, cooldown)
+	if err := b.SetRateLimited(ctx, cooldown); err != nil {
+		lb.logger.Printf("Failed to record rate-limited state for %s: %v", b.URL, err)
+	}
+}
+
+// HealthReport is the payload served by /admin/health.
+type HealthReport struct {
+	URL         string `json:"url"`
+	Online      bool   `json:"online"`
+	RateLimited bool   `json:"rateLimited"`
+}
+
+// Health returns the current online/rate-limited state of every backend.
+func (lb *LoadBalancer) Health() []HealthReport {
+	lb.mutex.RLock()
+	backends := make([]*backend.Backend, len(lb.backends))
+	copy(backends, lb.backends)
+	lb.mutex.RUnlock()
+
+	ctx := context.Background()
+	reports := make([]HealthReport, 0, len(backends))
+	for _, b := range backends {
+		reports = append(reports, HealthReport{
+			URL:         b.URL.String(),
+			Online:      b.Online(ctx),
+			RateLimited: b.IsRateLimited(ctx),
+		})
+	}
+	return reports
+}
@@ -0,0 +1,295 @@
+package balancer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"load-balancer/internal/backend"
+)
+
+// RPCReq is a single JSON-RPC call as sent by Ethereum clients, optionally
+// batched inside a top-level JSON array.
+type RPCReq struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// RPCRes is the matching JSON-RPC response.
+type RPCRes struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// RPCError mirrors the JSON-RPC 2.0 error object.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// MaxBatchSize is the hard ceiling on the number of calls a client may pack
+// into a single JSON-RPC batch request.
+const MaxBatchSize = 100
+
+// maxUpstreamWorkers bounds how many sub-group requests are in flight to
+// backends at once for a single client batch.
+const maxUpstreamWorkers = 8
+
+func errorResponse(id json.RawMessage, code int, message string) RPCRes {
+	return RPCRes{JSONRPC: "2.0", ID: id, Error: &RPCError{Code: code, Message: message}}
+}
+
+func writeJSONRPCError(w http.ResponseWriter, status int, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse(nil, code, message))
+}
+
+// isBatchRequest reports whether the request body is a JSON array, i.e. a
+// JSON-RPC batch, rather than a single JSON-RPC object.
+func isBatchRequest(body []byte) bool {
+	for _, b := range body {
+		switch b {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case '[':
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// group is a set of RPCReqs sharing a method, along with the index each one
+// occupied in the original batch so results can be reassembled in order.
+type group struct {
+	method  string
+	reqs    []RPCReq
+	indices []int
+}
+
+// splitBatch groups requests by method, preserving first-seen method order,
+// then slices each group into chunks of at most MaxUpstreamBatchSize so no
+// single upstream call exceeds it.
+func splitBatch(reqs []RPCReq, maxUpstreamBatchSize int) []group {
+	order := make([]string, 0)
+	byMethod := make(map[string]*group)
+
+	for i, req := range reqs {
+		g, ok := byMethod[req.Method]
+		if !ok {
+			g = &group{method: req.Method}
+			byMethod[req.Method] = g
+			order = append(order, req.Method)
+		}
+		g.reqs = append(g.reqs, req)
+		g.indices = append(g.indices, i)
+	}
+
+	var chunks []group
+	for _, method := range order {
+		g := byMethod[method]
+		for start := 0; start < len(g.reqs); start += maxUpstreamBatchSize {
+			end := start + maxUpstreamBatchSize
+			if end > len(g.reqs) {
+				end = len(g.reqs)
+			}
+			chunks = append(chunks, group{
+				method:  method,
+				reqs:    g.reqs[start:end],
+				indices: g.indices[start:end],
+			})
+		}
+	}
+
+	return chunks
+}
+
+// matchResponsesByID reassembles res into the order of reqs by comparing
+// JSON-RPC IDs, since JSON-RPC 2.0 does not require a batch response to
+// preserve the request order.
+func matchResponsesByID(reqs []RPCReq, res []RPCRes) []RPCRes {
+	pending := make(map[string][]RPCRes, len(res))
+	for _, r := range res {
+		key := string(r.ID)
+		pending[key] = append(pending[key], r)
+	}
+
+	matched := make([]RPCRes, len(reqs))
+	for i, req := range reqs {
+		key := string(req.ID)
+		if list := pending[key]; len(list) > 0 {
+			matched[i] = list[0]
+			pending[key] = list[1:]
+			continue
+		}
+		matched[i] = errorResponse(req.ID, -32603, "missing upstream response")
+	}
+
+	return matched
+}
+
+// serveBatch handles a JSON-RPC batch request: it fans each method's
+// sub-requests out to an independently-selected backend, bounded by a small
+// worker pool, and reassembles the ordered responses.
+func (lb *LoadBalancer) serveBatch(w http.ResponseWriter, r *http.Request, body []byte) {
+	start := time.Now()
+
+	var reqs []RPCReq
+	if err := json.Unmarshal(body, &reqs); err != nil {
+		writeJSONRPCError(w, http.StatusBadRequest, -32700, "parse error")
+		return
+	}
+
+	if len(reqs) == 0 {
+		writeJSONRPCError(w, http.StatusBadRequest, -32600, "invalid request")
+		return
+	}
+
+	if len(reqs) > MaxBatchSize {
+		lb.logger.Printf("Rejecting batch of %d requests from %s: exceeds MaxBatchSize (%d)", len(reqs), r.RemoteAddr, MaxBatchSize)
+		writeJSONRPCError(w, http.StatusBadRequest, -32600, "invalid request: batch size exceeds limit")
+		return
+	}
+
+	maxUpstreamBatchSize := lb.MaxUpstreamBatchSize
+	if maxUpstreamBatchSize <= 0 {
+		maxUpstreamBatchSize = 10
+	}
+
+	chunks := splitBatch(reqs, maxUpstreamBatchSize)
+	results := make([]RPCRes, len(reqs))
+
+	var (
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, maxUpstreamWorkers)
+		mu     sync.Mutex
+		maxDur = make(map[string]time.Duration)
+	)
+
+	for _, c := range chunks {
+		c := c
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			res, b, duration, err := lb.forwardBatchWithRetry(r.Context(), c)
+			if err != nil {
+				lb.logger.Printf("All backends exhausted forwarding %s batch: %v", c.method, err)
+				for i, idx := range c.indices {
+					results[idx] = errorResponse(c.reqs[i].ID, -32603, "internal error")
+				}
+				return
+			}
+
+			mu.Lock()
+			if duration > maxDur[b.URL.String()] {
+				maxDur[b.URL.String()] = duration
+			}
+			mu.Unlock()
+
+			matched := matchResponsesByID(c.reqs, res)
+			for i, idx := range c.indices {
+				results[idx] = matched[i]
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	ctx := context.Background()
+	for urlStr, d := range maxDur {
+		lb.rdb.HSet(ctx, "backends", urlStr, d.String())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+
+	lb.logger.Printf(
+		"[%s] Completed batch request: %s %s from %s | Size: %d | Duration: %v",
+		time.Now().Format(time.RFC3339), r.Method, r.URL.Path, r.RemoteAddr, len(reqs), time.Since(start),
+	)
+}
+
+// forwardBatch sends a single sub-batch to a backend as a JSON-RPC batch and
+// decodes the matching response array. A 5xx or 429 status is reported as an
+// error (with the status code) before any attempt to decode the body, so the
+// caller can route it through the same health/retry handling as single
+// requests instead of trusting a body that may not even be JSON.
+func (lb *LoadBalancer) forwardBatch(ctx context.Context, b *backend.Backend, reqs []RPCReq) ([]RPCRes, int, error) {
+	payload, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.URL.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return nil, resp.StatusCode, fmt.Errorf("backend %s returned status %d", b.URL, resp.StatusCode)
+	}
+
+	var res []RPCRes
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("decoding upstream batch response: %w", err)
+	}
+
+	return res, resp.StatusCode, nil
+}
+
+// forwardBatchWithRetry selects a backend and forwards c's sub-batch to it,
+// retrying against a different backend (and marking the failed one
+// unhealthy/rate-limited, same as serveWithRetry) up to MaxRetries times.
+func (lb *LoadBalancer) forwardBatchWithRetry(ctx context.Context, c group) ([]RPCRes, *backend.Backend, time.Duration, error) {
+	maxRetries := lb.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		b, err := lb.NextBackend()
+		if err != nil {
+			lastErr = err
+			break
+		}
+
+		attemptStart := time.Now()
+		res, status, err := lb.forwardBatch(ctx, b, c.reqs)
+		duration := time.Since(attemptStart)
+
+		if err != nil {
+			lastErr = err
+			if status == http.StatusTooManyRequests {
+				lb.markRateLimited(ctx, b, attempt)
+			} else {
+				lb.markUnhealthy(ctx, b, attempt, err)
+			}
+			continue
+		}
+
+		return res, b, duration, nil
+	}
+
+	return nil, nil, 0, lastErr
+}
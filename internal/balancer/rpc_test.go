@@ -0,0 +1,85 @@
+package balancer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func rawID(id int) json.RawMessage {
+	b, _ := json.Marshal(id)
+	return b
+}
+
+func TestSplitBatch(t *testing.T) {
+	reqs := []RPCReq{
+		{Method: "eth_chainId", ID: rawID(1)},
+		{Method: "eth_getBlockByNumber", ID: rawID(2)},
+		{Method: "eth_chainId", ID: rawID(3)},
+		{Method: "eth_chainId", ID: rawID(4)},
+	}
+
+	chunks := splitBatch(reqs, 2)
+
+	var gotMethods []string
+	var gotIndices [][]int
+	for _, c := range chunks {
+		gotMethods = append(gotMethods, c.method)
+		gotIndices = append(gotIndices, c.indices)
+	}
+
+	wantMethods := []string{"eth_chainId", "eth_chainId", "eth_getBlockByNumber"}
+	if !reflect.DeepEqual(gotMethods, wantMethods) {
+		t.Fatalf("methods = %v, want %v", gotMethods, wantMethods)
+	}
+
+	wantIndices := [][]int{{0, 2}, {3}, {1}}
+	if !reflect.DeepEqual(gotIndices, wantIndices) {
+		t.Fatalf("indices = %v, want %v", gotIndices, wantIndices)
+	}
+}
+
+func TestMatchResponsesByID(t *testing.T) {
+	reqs := []RPCReq{
+		{ID: rawID(1)},
+		{ID: rawID(2)},
+		{ID: rawID(3)},
+	}
+
+	// Upstream returned the responses out of order, and omitted one.
+	res := []RPCRes{
+		{ID: rawID(2), Result: json.RawMessage(`"b"`)},
+		{ID: rawID(1), Result: json.RawMessage(`"a"`)},
+	}
+
+	matched := matchResponsesByID(reqs, res)
+
+	if string(matched[0].Result) != `"a"` {
+		t.Errorf("matched[0].Result = %s, want \"a\"", matched[0].Result)
+	}
+	if string(matched[1].Result) != `"b"` {
+		t.Errorf("matched[1].Result = %s, want \"b\"", matched[1].Result)
+	}
+	if matched[2].Error == nil {
+		t.Errorf("matched[2].Error = nil, want missing-response error for unanswered request")
+	}
+}
+
+func TestIsBatchRequest(t *testing.T) {
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{`[{"method":"eth_chainId"}]`, true},
+		{`  [1,2,3]`, true},
+		{`{"method":"eth_chainId"}`, false},
+		{`   {"a":1}`, false},
+		{``, false},
+	}
+
+	for _, tt := range tests {
+		if got := isBatchRequest([]byte(tt.body)); got != tt.want {
+			t.Errorf("isBatchRequest(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package balancer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"load-balancer/internal/backend"
+)
+
+// Default WebSocket timeouts, used when the corresponding LoadBalancer field
+// is unset.
+const (
+	defaultWSHandshakeTimeout = 10 * time.Second
+	defaultWSReadTimeout      = 2 * time.Minute
+	defaultWSWriteTimeout     = 10 * time.Second
+)
+
+// defaultWSMethodWhitelist is the set of JSON-RPC methods a client may send
+// over an established WebSocket connection.
+var defaultWSMethodWhitelist = map[string]bool{
+	"eth_subscribe":   true,
+	"eth_unsubscribe": true,
+	"eth_chainId":     true,
+}
+
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// IsWebSocketUpgrade reports whether r is requesting a WebSocket upgrade.
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// ServeWebSocket upgrades the client connection, selects a backend, dials
+// its WebSocket endpoint, and pumps frames bidirectionally between the two,
+// enforcing a JSON-RPC method whitelist on client-to-backend frames.
+func (lb *LoadBalancer) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	handshakeTimeout := lb.WSHandshakeTimeout
+	if handshakeTimeout <= 0 {
+		handshakeTimeout = defaultWSHandshakeTimeout
+	}
+
+	b, backendConn, err := lb.dialWSBackendWithRetry(handshakeTimeout)
+	if err != nil {
+		http.Error(w, "No backend available", http.StatusServiceUnavailable)
+		lb.logger.Printf("WebSocket: all backends exhausted for %s: %v", r.RemoteAddr, err)
+		return
+	}
+	defer backendConn.Close()
+
+	wsUpgrader.HandshakeTimeout = handshakeTimeout
+	clientConn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		lb.logger.Printf("WebSocket: upgrade failed for %s: %v", r.RemoteAddr, err)
+		return
+	}
+	defer clientConn.Close()
+
+	lb.logger.Printf("WebSocket: %s connected via backend %s", r.RemoteAddr, b.URL)
+
+	var pending pendingSend
+	done := make(chan struct{}, 2)
+
+	go lb.pumpClientToBackend(clientConn, backendConn, &pending, done)
+	go lb.pumpBackendToClient(backendConn, clientConn, b, &pending, done)
+
+	<-done
+}
+
+// dialWSBackendWithRetry selects a backend and dials its WebSocket endpoint,
+// retrying against a different backend (marking the failed one unhealthy,
+// same as serveWithRetry) up to MaxRetries times if the chosen backend has no
+// WSURL or the dial fails.
+func (lb *LoadBalancer) dialWSBackendWithRetry(handshakeTimeout time.Duration) (*backend.Backend, *websocket.Conn, error) {
+	maxRetries := lb.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	dialer := websocket.Dialer{HandshakeTimeout: handshakeTimeout}
+	ctx := context.Background()
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		b, err := lb.NextBackend()
+		if err != nil {
+			lastErr = err
+			break
+		}
+
+		if b.WSURL == nil {
+			lastErr = fmt.Errorf("backend %s has no WebSocket endpoint", b.URL)
+			lb.markUnhealthy(ctx, b, attempt, lastErr)
+			continue
+		}
+
+		backendConn, _, err := dialer.Dial(b.WSURL.String(), nil)
+		if err != nil {
+			lastErr = fmt.Errorf("dialing backend %s: %w", b.WSURL, err)
+			lb.markUnhealthy(ctx, b, attempt, lastErr)
+			continue
+		}
+
+		return b, backendConn, nil
+	}
+
+	return nil, nil, lastErr
+}
+
+// pendingSend tracks when the most recent whitelisted client frame was
+// forwarded to the backend, so the next backend frame's latency can be
+// attributed to it.
+type pendingSend struct {
+	mutex  sync.Mutex
+	sentAt time.Time
+}
+
+func (p *pendingSend) markSent() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.sentAt = time.Now()
+}
+
+func (p *pendingSend) sample() (time.Duration, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.sentAt.IsZero() {
+		return 0, false
+	}
+	d := time.Since(p.sentAt)
+	p.sentAt = time.Time{}
+	return d, true
+}
+
+func (lb *LoadBalancer) pumpClientToBackend(clientConn, backendConn *websocket.Conn, pending *pendingSend, done chan<- struct{}) {
+	defer func() { done <- struct{}{} }()
+
+	readTimeout := lb.WSReadTimeout
+	if readTimeout <= 0 {
+		readTimeout = defaultWSReadTimeout
+	}
+	writeTimeout := lb.WSWriteTimeout
+	if writeTimeout <= 0 {
+		writeTimeout = defaultWSWriteTimeout
+	}
+
+	whitelist := lb.WSMethodWhitelist
+	if whitelist == nil {
+		whitelist = defaultWSMethodWhitelist
+	}
+
+	for {
+		clientConn.SetReadDeadline(time.Now().Add(readTimeout))
+		msgType, msg, err := clientConn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var req RPCReq
+		if err := json.Unmarshal(msg, &req); err == nil && req.Method != "" && !whitelist[req.Method] {
+			clientConn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			clientConn.WriteJSON(errorResponse(req.ID, -32601, "method not allowed over this connection"))
+			continue
+		}
+
+		pending.markSent()
+
+		backendConn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := backendConn.WriteMessage(msgType, msg); err != nil {
+			return
+		}
+	}
+}
+
+// isSubscriptionNotification reports whether msg is an eth_subscription push
+// (an unsolicited notification carrying a "method", not a reply to any
+// client call) rather than a response to a pending request, so it doesn't
+// get sampled as that request's response time.
+func isSubscriptionNotification(msg []byte) bool {
+	var notification struct {
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(msg, &notification); err != nil {
+		return false
+	}
+	return notification.Method == "eth_subscription"
+}
+
+func (lb *LoadBalancer) pumpBackendToClient(backendConn, clientConn *websocket.Conn, b *backend.Backend, pending *pendingSend, done chan<- struct{}) {
+	defer func() { done <- struct{}{} }()
+
+	readTimeout := lb.WSReadTimeout
+	if readTimeout <= 0 {
+		readTimeout = defaultWSReadTimeout
+	}
+	writeTimeout := lb.WSWriteTimeout
+	if writeTimeout <= 0 {
+		writeTimeout = defaultWSWriteTimeout
+	}
+
+	for {
+		backendConn.SetReadDeadline(time.Now().Add(readTimeout))
+		msgType, msg, err := backendConn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		if !isSubscriptionNotification(msg) {
+			if d, ok := pending.sample(); ok {
+				lb.rdb.HSet(context.Background(), "backends", b.URL.String(), d.String())
+			}
+		}
+
+		clientConn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := clientConn.WriteMessage(msgType, msg); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Package cache provides response caching for idempotent JSON-RPC methods,
+// so repeated calls for immutable data (a finalized block, a transaction
+// receipt) don't need to hit a backend at all.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCacheMiss is returned by Get when the key is not present or has
+// expired.
+var ErrCacheMiss = errors.New("cache miss")
+
+// RPCCache stores JSON-RPC responses keyed by method and params.
+type RPCCache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Put(ctx context.Context, key string, value string, ttl time.Duration) error
+	Clear(ctx context.Context) error
+}
+
+type lruEntry struct {
+	key     string
+	value   string
+	expires time.Time
+}
+
+// LRUCache is a size-bounded, in-process cache for hot keys. It is not
+// shared across load balancer replicas; pair it with RedisCache for that.
+type LRUCache struct {
+	mutex    sync.Mutex
+	maxItems int
+	order    *list.List // front = most recently used
+	items    map[string]*list.Element
+}
+
+// NewLRUCache creates an LRUCache holding at most maxItems entries.
+func NewLRUCache(maxItems int) *LRUCache {
+	return &LRUCache{
+		maxItems: maxItems,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *LRUCache) Get(ctx context.Context, key string) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return "", ErrCacheMiss
+	}
+
+	entry := elem.Value.(*lruEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.items, key)
+		return "", ErrCacheMiss
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.value, nil
+}
+
+func (c *LRUCache) Put(ctx context.Context, key string, value string, ttl time.Duration) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*lruEntry).value = value
+		elem.Value.(*lruEntry).expires = time.Now().Add(ttl)
+		c.order.MoveToFront(elem)
+		return nil
+	}
+
+	elem := c.order.PushFront(&lruEntry{key: key, value: value, expires: time.Now().Add(ttl)})
+	c.items[key] = elem
+
+	for c.order.Len() > c.maxItems {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+
+	return nil
+}
+
+func (c *LRUCache) Clear(ctx context.Context) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+	return nil
+}
+
+// TieredCache checks an in-process LRUCache before falling back to a shared
+// backing cache (typically RedisCache), and populates the LRU on a
+// miss-then-hit so hot keys stop round-tripping to Redis.
+type TieredCache struct {
+	hot     *LRUCache
+	backing RPCCache
+}
+
+// NewTieredCache wraps backing with an LRUCache holding at most hotItems
+// entries.
+func NewTieredCache(backing RPCCache, hotItems int) *TieredCache {
+	return &TieredCache{hot: NewLRUCache(hotItems), backing: backing}
+}
+
+func (c *TieredCache) Get(ctx context.Context, key string) (string, error) {
+	if value, err := c.hot.Get(ctx, key); err == nil {
+		return value, nil
+	}
+
+	value, err := c.backing.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	// ttl is unknown at this point, so re-cache the value hot for a short,
+	// fixed window rather than threading the original TTL through the
+	// backing store's Get.
+	c.hot.Put(ctx, key, value, hotCacheTTL)
+	return value, nil
+}
+
+func (c *TieredCache) Put(ctx context.Context, key string, value string, ttl time.Duration) error {
+	c.hot.Put(ctx, key, value, ttl)
+	return c.backing.Put(ctx, key, value, ttl)
+}
+
+func (c *TieredCache) Clear(ctx context.Context) error {
+	c.hot.Clear(ctx)
+	return c.backing.Clear(ctx)
+}
+
+// hotCacheTTL bounds how long a value repopulated into the LRU tier from a
+// backing-store hit stays hot, independent of the backing store's own TTL.
+const hotCacheTTL = 10 * time.Second
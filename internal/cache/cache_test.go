@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLRUCacheEviction(t *testing.T) {
+	c := NewLRUCache(2)
+	ctx := context.Background()
+
+	c.Put(ctx, "a", "1", time.Minute)
+	c.Put(ctx, "b", "2", time.Minute)
+	c.Put(ctx, "c", "3", time.Minute) // evicts "a", the least recently used
+
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get(a) err = %v, want ErrCacheMiss", err)
+	}
+	if v, err := c.Get(ctx, "b"); err != nil || v != "2" {
+		t.Errorf("Get(b) = %q, %v, want \"2\", nil", v, err)
+	}
+	if v, err := c.Get(ctx, "c"); err != nil || v != "3" {
+		t.Errorf("Get(c) = %q, %v, want \"3\", nil", v, err)
+	}
+}
+
+func TestLRUCacheExpiry(t *testing.T) {
+	c := NewLRUCache(10)
+	ctx := context.Background()
+
+	c.Put(ctx, "k", "v", -time.Second) // already expired
+
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get(k) err = %v, want ErrCacheMiss", err)
+	}
+}
+
+// fakeCache is a minimal RPCCache used to observe TieredCache's fallthrough
+// behavior without depending on a real Redis instance.
+type fakeCache struct {
+	values map[string]string
+	gets   int
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{values: make(map[string]string)}
+}
+
+func (f *fakeCache) Get(ctx context.Context, key string) (string, error) {
+	f.gets++
+	v, ok := f.values[key]
+	if !ok {
+		return "", ErrCacheMiss
+	}
+	return v, nil
+}
+
+func (f *fakeCache) Put(ctx context.Context, key string, value string, ttl time.Duration) error {
+	f.values[key] = value
+	return nil
+}
+
+func (f *fakeCache) Clear(ctx context.Context) error {
+	f.values = make(map[string]string)
+	return nil
+}
+
+func TestTieredCachePopulatesHotTierOnBackingHit(t *testing.T) {
+	backing := newFakeCache()
+	backing.values["k"] = "v"
+
+	tc := NewTieredCache(backing, 10)
+	ctx := context.Background()
+
+	if v, err := tc.Get(ctx, "k"); err != nil || v != "v" {
+		t.Fatalf("Get(k) = %q, %v, want \"v\", nil", v, err)
+	}
+	if backing.gets != 1 {
+		t.Fatalf("backing.gets = %d, want 1", backing.gets)
+	}
+
+	// Second read should be served from the hot LRU tier, not the backing store.
+	if v, err := tc.Get(ctx, "k"); err != nil || v != "v" {
+		t.Fatalf("Get(k) (hot) = %q, %v, want \"v\", nil", v, err)
+	}
+	if backing.gets != 1 {
+		t.Errorf("backing.gets = %d after hot hit, want still 1", backing.gets)
+	}
+}
+
+func TestTieredCacheMissPropagates(t *testing.T) {
+	tc := NewTieredCache(newFakeCache(), 10)
+
+	if _, err := tc.Get(context.Background(), "missing"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get(missing) err = %v, want ErrCacheMiss", err)
+	}
+}
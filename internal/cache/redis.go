@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisCache stores cached responses in Redis, so a hit on one load
+// balancer replica is visible to all the others.
+type RedisCache struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+// NewRedisCache wraps an existing Redis client. prefix namespaces cache keys
+// (e.g. "rpccache:") so they don't collide with the "backends" hash.
+func NewRedisCache(rdb *redis.Client, prefix string) *RedisCache {
+	return &RedisCache{rdb: rdb, prefix: prefix}
+}
+
+func (c *RedisCache) fullKey(key string) string {
+	return c.prefix + key
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) (string, error) {
+	value, err := c.rdb.Get(ctx, c.fullKey(key)).Result()
+	if err == redis.Nil {
+		return "", ErrCacheMiss
+	}
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+func (c *RedisCache) Put(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return c.rdb.Set(ctx, c.fullKey(key), value, ttl).Err()
+}
+
+// Clear drops every cached entry under this cache's prefix. It is intended
+// to be called on reorg notifications, when cached results may now be stale.
+func (c *RedisCache) Clear(ctx context.Context) error {
+	iter := c.rdb.Scan(ctx, 0, c.prefix+"*", 0).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.rdb.Del(ctx, keys...).Err()
+}
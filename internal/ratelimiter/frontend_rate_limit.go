@@ -0,0 +1,214 @@
+package ratelimiter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-redis/redis/v8"
+)
+
+// MethodOverride sets a stricter (or looser) rate per JSON-RPC method.
+type MethodOverride struct {
+	RatePerSecond float64 `toml:"rate_per_second"`
+	Burst         int     `toml:"burst"`
+}
+
+// FrontendConfig is the TOML-loaded configuration for FrontendRateLimiter.
+type FrontendConfig struct {
+	RatePerSecond       float64                   `toml:"rate_per_second"`
+	Burst               int                       `toml:"burst"`
+	MethodOverrides     map[string]MethodOverride `toml:"method_overrides"`
+	UserAgentExemptions []string                  `toml:"user_agent_exemptions"`
+	OriginExemptions    []string                  `toml:"origin_exemptions"`
+}
+
+// LoadFrontendConfig reads a FrontendConfig from a TOML file.
+func LoadFrontendConfig(path string) (FrontendConfig, error) {
+	var cfg FrontendConfig
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		return FrontendConfig{}, err
+	}
+	return cfg, nil
+}
+
+// FrontendRateLimiter enforces a per-client-IP request budget, independent
+// of MethodRateLimiter's per-nodeId budget. State lives in Redis so the
+// limit is shared across load balancer replicas.
+type FrontendRateLimiter struct {
+	rdb              *redis.Client
+	rate             float64
+	burst            int
+	methodOverrides  map[string]MethodOverride
+	uaExemptions     []*regexp.Regexp
+	originExemptions []*regexp.Regexp
+}
+
+// NewFrontendRateLimiter builds a FrontendRateLimiter from cfg, compiling its
+// exemption patterns up front so Allow never has to return a regexp error.
+func NewFrontendRateLimiter(rdb *redis.Client, cfg FrontendConfig) (*FrontendRateLimiter, error) {
+	uaExemptions, err := compilePatterns(cfg.UserAgentExemptions)
+	if err != nil {
+		return nil, fmt.Errorf("compiling user_agent_exemptions: %w", err)
+	}
+	originExemptions, err := compilePatterns(cfg.OriginExemptions)
+	if err != nil {
+		return nil, fmt.Errorf("compiling origin_exemptions: %w", err)
+	}
+
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+	rate := cfg.RatePerSecond
+	if rate <= 0 {
+		rate = float64(burst)
+	}
+
+	return &FrontendRateLimiter{
+		rdb:              rdb,
+		rate:             rate,
+		burst:            burst,
+		methodOverrides:  cfg.MethodOverrides,
+		uaExemptions:     uaExemptions,
+		originExemptions: originExemptions,
+	}, nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// tokenBucketScript atomically refills and draws from a token bucket stored
+// as a Redis hash {tokens, last}, so concurrent load balancer replicas never
+// race on the read-modify-write. KEYS[1] is the bucket key; ARGV is
+// (rate, burst, now-as-unix-seconds-float).
+var tokenBucketScript = redis.NewScript(`
+local tokens = tonumber(redis.call("HGET", KEYS[1], "tokens"))
+local last = tonumber(redis.call("HGET", KEYS[1], "last"))
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+if tokens == nil or last == nil then
+	tokens = burst
+	last = now
+end
+
+tokens = math.min(burst, tokens + (now - last) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	allowed = 1
+	tokens = tokens - 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tokens, "last", now)
+redis.call("EXPIRE", KEYS[1], 60)
+
+return allowed
+`)
+
+// Allow reports whether r should proceed, or has exceeded this client IP's
+// request budget. The budget is a token bucket refilling at RatePerSecond up
+// to Burst tokens, so a configured rate below burst actually constrains
+// sustained throughput instead of just the first second's worth of requests.
+func (f *FrontendRateLimiter) Allow(r *http.Request) (bool, error) {
+	if f.isExempt(r) {
+		return true, nil
+	}
+
+	ip := clientIP(r)
+	rate := f.rate
+	burst := f.burst
+	if method, ok := requestMethod(r); ok {
+		if override, ok := f.methodOverrides[method]; ok {
+			if override.RatePerSecond > 0 {
+				rate = override.RatePerSecond
+			}
+			if override.Burst > 0 {
+				burst = override.Burst
+			}
+		}
+	}
+
+	ctx := context.Background()
+	bucketKey := fmt.Sprintf("frontend_rl:%s", ip)
+
+	allowed, err := tokenBucketScript.Run(ctx, f.rdb, []string{bucketKey}, rate, burst, float64(time.Now().UnixNano())/1e9).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return allowed == 1, nil
+}
+
+func (f *FrontendRateLimiter) isExempt(r *http.Request) bool {
+	userAgent := r.Header.Get("User-Agent")
+	for _, re := range f.uaExemptions {
+		if re.MatchString(userAgent) {
+			return true
+		}
+	}
+
+	origin := r.Header.Get("Origin")
+	for _, re := range f.originExemptions {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP derives the client's address from X-Forwarded-For, falling back
+// to RemoteAddr.
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		parts := strings.Split(forwarded, ",")
+		return strings.TrimSpace(parts[0])
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requestMethod peeks at the JSON-RPC method in r's body without consuming
+// it for downstream handlers.
+func requestMethod(r *http.Request) (string, bool) {
+	if r.Body == nil {
+		return "", false
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return "", false
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var reqBody requestBody
+	if err := json.Unmarshal(body, &reqBody); err != nil {
+		return "", false
+	}
+
+	return reqBody.Method, reqBody.Method != ""
+}
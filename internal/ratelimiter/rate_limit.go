@@ -54,7 +54,9 @@ func (mrl *MethodRateLimiter) Allow(r *http.Request) (bool, error) {
 
     var reqBody requestBody
     if err := json.Unmarshal(body, &reqBody); err != nil {
-        return false, err
+        // Not a single JSON-RPC object (e.g. a batch request) - leave it to
+        // the batch-splitting path rather than failing the request outright.
+        return true, nil
     }
 
     // Check if the method should be rate limited
@@ -0,0 +1,187 @@
+package ratelimiter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrSenderRateLimited describes a transaction whose sender has exceeded its
+// budget. SenderRateLimiter.Allow does not return it: Allow reports that
+// case as false with a nil error.
+var ErrSenderRateLimited = errors.New("sender is over rate limit")
+
+// InvalidTransactionError wraps a failure to parse or recover the sender of
+// an eth_sendRawTransaction call. It is the client's fault (malformed params,
+// bad hex, bad RLP, a signature that doesn't recover), not a rate-limiter
+// failure, so callers should report it as a JSON-RPC invalid-params error
+// rather than a 500.
+type InvalidTransactionError struct {
+	Err error
+}
+
+func (e *InvalidTransactionError) Error() string {
+	return fmt.Sprintf("invalid eth_sendRawTransaction: %v", e.Err)
+}
+
+func (e *InvalidTransactionError) Unwrap() error {
+	return e.Err
+}
+
+type sendRawTxParams []string
+
+// sendRawTxCall is the shape of a single eth_sendRawTransaction JSON-RPC
+// call, whether it arrives standalone or as one element of a batch.
+type sendRawTxCall struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// SenderRateLimiter rate-limits eth_sendRawTransaction calls by the
+// transaction's recovered sender address, rather than by IP or nodeId,
+// since an attacker can trivially rotate either of those while spamming
+// transactions from one account. Like FrontendRateLimiter, its state lives
+// in Redis, shared across load balancer replicas and
+// naturally evicted via key TTLs rather than growing without bound.
+type SenderRateLimiter struct {
+	rdb   *redis.Client
+	rate  float64
+	burst int
+}
+
+// senderCacheTTL is how long a recovered sender is cached per tx hash, so a
+// client retrying the same signed transaction doesn't redo ECDSA recovery.
+const senderCacheTTL = 30 * time.Second
+
+// NewSenderRateLimiter creates a SenderRateLimiter with the given
+// rate/burst, typically stricter than read-method limits (e.g. 1 rps / 5
+// burst).
+func NewSenderRateLimiter(rdb *redis.Client, rate float64, burst int) *SenderRateLimiter {
+	return &SenderRateLimiter{rdb: rdb, rate: rate, burst: burst}
+}
+
+// Allow reports whether r's eth_sendRawTransaction call(s) should proceed,
+// whether r is a single JSON-RPC object or a batch array. Any other method
+// always allows the request, leaving it to the existing per-method/per-IP
+// limiters. A batch is rejected as a whole if any one of its
+// eth_sendRawTransaction calls is malformed or over budget - a client
+// packing the call into a one-element batch to dodge this limiter must not
+// fare any better than calling it directly.
+func (srl *SenderRateLimiter) Allow(r *http.Request) (bool, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return false, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var call sendRawTxCall
+	if err := json.Unmarshal(body, &call); err == nil {
+		return srl.allowCall(call)
+	}
+
+	var calls []sendRawTxCall
+	if err := json.Unmarshal(body, &calls); err != nil {
+		// Neither a single JSON-RPC object nor a batch array - leave it to
+		// the rest of the request pipeline to reject.
+		return true, nil
+	}
+
+	for _, c := range calls {
+		if allowed, err := srl.allowCall(c); !allowed || err != nil {
+			return allowed, err
+		}
+	}
+
+	return true, nil
+}
+
+// allowCall applies the sender rate limit to a single decoded call, doing
+// nothing for any method other than eth_sendRawTransaction.
+func (srl *SenderRateLimiter) allowCall(call sendRawTxCall) (bool, error) {
+	if call.Method != "eth_sendRawTransaction" {
+		return true, nil
+	}
+	var params sendRawTxParams
+	if len(call.Params) > 0 {
+		if err := json.Unmarshal(call.Params, &params); err != nil {
+			return false, &InvalidTransactionError{Err: fmt.Errorf("decoding params: %w", err)}
+		}
+	}
+	if len(params) == 0 {
+		return false, &InvalidTransactionError{Err: errors.New("eth_sendRawTransaction missing raw transaction param")}
+	}
+
+	sender, err := srl.recoverSender(params[0])
+	if err != nil {
+		return false, &InvalidTransactionError{Err: err}
+	}
+
+	return srl.allowSender(sender)
+}
+
+// senderCacheKey namespaces a recovered-sender cache entry by tx hash.
+func senderCacheKey(txHash string) string {
+	return "sender_rl:txcache:" + txHash
+}
+
+// senderBucketKey namespaces a sender's token bucket.
+func senderBucketKey(sender string) string {
+	return "sender_rl:bucket:" + sender
+}
+
+// recoverSender decodes rawTxHex and recovers its signer, honoring EIP-155
+// (legacy), EIP-2930 (access-list), and EIP-1559 (dynamic-fee) transactions,
+// whose types.Transaction.UnmarshalBinary dispatches on the 0x01/0x02 type
+// prefix. Recent recoveries are cached in Redis per tx hash, with a TTL, to
+// avoid repeating the secp256k1 recovery on client retries without growing
+// an unbounded in-process map.
+func (srl *SenderRateLimiter) recoverSender(rawTxHex string) (string, error) {
+	rawTx, err := hexutil.Decode(rawTxHex)
+	if err != nil {
+		return "", err
+	}
+
+	var tx types.Transaction
+	if err := tx.UnmarshalBinary(rawTx); err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	cacheKey := senderCacheKey(tx.Hash().Hex())
+
+	if sender, err := srl.rdb.Get(ctx, cacheKey).Result(); err == nil {
+		return sender, nil
+	}
+
+	signer := types.LatestSignerForChainID(tx.ChainId())
+	from, err := types.Sender(signer, &tx)
+	if err != nil {
+		return "", err
+	}
+	sender := from.Hex()
+
+	srl.rdb.Set(ctx, cacheKey, sender, senderCacheTTL)
+
+	return sender, nil
+}
+
+func (srl *SenderRateLimiter) allowSender(sender string) (bool, error) {
+	ctx := context.Background()
+	now := float64(time.Now().UnixNano()) / 1e9
+
+	allowed, err := tokenBucketScript.Run(ctx, srl.rdb, []string{senderBucketKey(sender)}, srl.rate, srl.burst, now).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return allowed == 1, nil
+}
@@ -0,0 +1,168 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-redis/redis/v8"
+)
+
+func newTestSenderLimiter(t *testing.T, rate float64, burst int) *SenderRateLimiter {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewSenderRateLimiter(rdb, rate, burst)
+}
+
+func signedRawTxHex(t *testing.T, nonce uint64) string {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := types.NewTransaction(nonce, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(0), 21000, big.NewInt(1), nil)
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	signedTx, err := types.SignTx(tx, signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := signedTx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hexutil.Encode(raw)
+}
+
+func TestRecoverSenderRoundTrip(t *testing.T) {
+	srl := newTestSenderLimiter(t, 1, 5)
+	rawTxHex := signedRawTxHex(t, 0)
+
+	sender, err := srl.recoverSender(rawTxHex)
+	if err != nil {
+		t.Fatalf("recoverSender: %v", err)
+	}
+	if sender == "" {
+		t.Fatal("recoverSender returned empty sender")
+	}
+
+	// Second call should hit the Redis cache and return the same sender.
+	cached, err := srl.recoverSender(rawTxHex)
+	if err != nil {
+		t.Fatalf("recoverSender (cached): %v", err)
+	}
+	if cached != sender {
+		t.Errorf("cached sender = %s, want %s", cached, sender)
+	}
+}
+
+func TestRecoverSenderRejectsGarbage(t *testing.T) {
+	srl := newTestSenderLimiter(t, 1, 5)
+
+	if _, err := srl.recoverSender("not-hex"); err == nil {
+		t.Error("recoverSender(\"not-hex\") = nil error, want error")
+	}
+	if _, err := srl.recoverSender("0xdeadbeef"); err == nil {
+		t.Error("recoverSender(0xdeadbeef) = nil error, want error for malformed RLP")
+	}
+}
+
+func TestAllowSenderTokenBucket(t *testing.T) {
+	srl := newTestSenderLimiter(t, 1, 3)
+
+	for i := 0; i < 3; i++ {
+		allowed, err := srl.allowSender("0xsender")
+		if err != nil {
+			t.Fatalf("allowSender: %v", err)
+		}
+		if !allowed {
+			t.Fatalf("call %d: allowSender = false, want true (within burst)", i)
+		}
+	}
+
+	allowed, err := srl.allowSender("0xsender")
+	if err != nil {
+		t.Fatalf("allowSender: %v", err)
+	}
+	if allowed {
+		t.Error("allowSender = true after burst exhausted, want false")
+	}
+
+	// A different sender has its own independent budget.
+	allowed, err = srl.allowSender("0xother")
+	if err != nil {
+		t.Fatalf("allowSender: %v", err)
+	}
+	if !allowed {
+		t.Error("allowSender for a distinct sender = false, want true")
+	}
+}
+
+func newRequestWithBody(t *testing.T, body string) *http.Request {
+	t.Helper()
+	return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+}
+
+func sendRawTxBody(rawTxHex string) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","method":"eth_sendRawTransaction","params":["%s"],"id":1}`, rawTxHex)
+}
+
+func TestAllowSingleEthSendRawTransaction(t *testing.T) {
+	srl := newTestSenderLimiter(t, 1, 1)
+	rawTxHex := signedRawTxHex(t, 0)
+
+	allowed, err := srl.Allow(newRequestWithBody(t, sendRawTxBody(rawTxHex)))
+	if err != nil || !allowed {
+		t.Fatalf("first call: allowed=%v err=%v, want true, nil", allowed, err)
+	}
+
+	// Same sender, burst of 1 exhausted - denied.
+	allowed, err = srl.Allow(newRequestWithBody(t, sendRawTxBody(rawTxHex)))
+	if err != nil || allowed {
+		t.Fatalf("second call: allowed=%v err=%v, want false, nil", allowed, err)
+	}
+}
+
+func TestAllowRejectsBatchWrappedSenderBypass(t *testing.T) {
+	srl := newTestSenderLimiter(t, 1, 1)
+	rawTxHex := signedRawTxHex(t, 0)
+
+	// Exhaust the sender's budget via a direct call first.
+	if allowed, err := srl.Allow(newRequestWithBody(t, sendRawTxBody(rawTxHex))); err != nil || !allowed {
+		t.Fatalf("priming call: allowed=%v err=%v, want true, nil", allowed, err)
+	}
+
+	// The same sender wraps the next call in a one-element batch, trying to
+	// dodge the limiter the way a request body that fails to unmarshal into
+	// a single JSON-RPC object used to be let through unconditionally.
+	batchBody := "[" + sendRawTxBody(rawTxHex) + "]"
+	allowed, err := srl.Allow(newRequestWithBody(t, batchBody))
+	if err != nil {
+		t.Fatalf("batched call: unexpected error %v", err)
+	}
+	if allowed {
+		t.Error("batched eth_sendRawTransaction bypassed the sender rate limit, want denied")
+	}
+}
+
+func TestAllowBatchOfNonSendRawTransactionCalls(t *testing.T) {
+	srl := newTestSenderLimiter(t, 1, 1)
+
+	batchBody := `[{"jsonrpc":"2.0","method":"eth_chainId","id":1},{"jsonrpc":"2.0","method":"eth_blockNumber","id":2}]`
+	allowed, err := srl.Allow(newRequestWithBody(t, batchBody))
+	if err != nil || !allowed {
+		t.Fatalf("allowed=%v err=%v, want true, nil for a batch with no eth_sendRawTransaction calls", allowed, err)
+	}
+}